@@ -0,0 +1,103 @@
+package mediadupes
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestClaimDestPathSuffixesDistinctBasenameCollision verifies that two
+// different source files landing on the same dir+base get distinct claimed
+// paths, the second one suffixed.
+func TestClaimDestPathSuffixesDistinctBasenameCollision(t *testing.T) {
+	srcDir := t.TempDir()
+	destDir := t.TempDir()
+
+	a := writeTempFile(t, srcDir, "a.jpg", []byte("AAAA"))
+	b := writeTempFile(t, srcDir, "b.jpg", []byte("BBBB"))
+
+	cfg := &Config{HashAlgo: "md5"}
+	ps := NewPipelineState(NoopProgressReporter{})
+	defer ps.Close()
+
+	first := ps.claimDestPath(destDir, "photo.jpg", &FileInfo{Path: a, Size: 4}, cfg)
+	if err := os.WriteFile(first, []byte("AAAA"), 0644); err != nil {
+		t.Fatalf("write %s: %v", first, err)
+	}
+
+	second := ps.claimDestPath(destDir, "photo.jpg", &FileInfo{Path: b, Size: 4}, cfg)
+	if second == first {
+		t.Fatalf("expected a distinct path for a different source file, got %q for both", first)
+	}
+	if filepath.Dir(second) != destDir {
+		t.Fatalf("expected suffixed path still under %q, got %q", destDir, second)
+	}
+}
+
+// TestClaimDestPathReusesSamePathForSameSource verifies that calling
+// claimDestPath twice for the same source file returns the same claimed
+// path rather than growing a new suffix each time.
+func TestClaimDestPathReusesSamePathForSameSource(t *testing.T) {
+	srcDir := t.TempDir()
+	destDir := t.TempDir()
+	a := writeTempFile(t, srcDir, "a.jpg", []byte("AAAA"))
+
+	cfg := &Config{HashAlgo: "md5"}
+	ps := NewPipelineState(NoopProgressReporter{})
+	defer ps.Close()
+
+	fi := &FileInfo{Path: a, Size: 4}
+	first := ps.claimDestPath(destDir, "photo.jpg", fi, cfg)
+	second := ps.claimDestPath(destDir, "photo.jpg", fi, cfg)
+
+	if first != second {
+		t.Fatalf("expected the same claimed path on re-claim, got %q then %q", first, second)
+	}
+}
+
+// TestClaimDestPathIdempotentAcrossReruns verifies that a pre-existing dest
+// file with the same size and content hash as fi (the "re-run" case: a
+// previous run already placed this exact file) is reused rather than
+// suffixed, even though nothing has claimed it yet this run.
+func TestClaimDestPathIdempotentAcrossReruns(t *testing.T) {
+	srcDir := t.TempDir()
+	destDir := t.TempDir()
+	a := writeTempFile(t, srcDir, "a.jpg", []byte("identical"))
+
+	existing := filepath.Join(destDir, "photo.jpg")
+	if err := os.WriteFile(existing, []byte("identical"), 0644); err != nil {
+		t.Fatalf("seed existing dest file: %v", err)
+	}
+
+	cfg := &Config{HashAlgo: "md5"}
+	ps := NewPipelineState(NoopProgressReporter{})
+	defer ps.Close()
+
+	got := ps.claimDestPath(destDir, "photo.jpg", &FileInfo{Path: a, Size: 9}, cfg)
+	if got != existing {
+		t.Fatalf("expected re-run to reuse %q, got %q", existing, got)
+	}
+}
+
+// TestClaimDestPathSuffixesOnRealCollision verifies that a pre-existing
+// dest file with different content than fi is treated as a genuine
+// collision and fi gets suffixed instead of clobbering it.
+func TestClaimDestPathSuffixesOnRealCollision(t *testing.T) {
+	srcDir := t.TempDir()
+	destDir := t.TempDir()
+	a := writeTempFile(t, srcDir, "a.jpg", []byte("new content"))
+
+	existing := filepath.Join(destDir, "photo.jpg")
+	if err := os.WriteFile(existing, []byte("other content!"), 0644); err != nil {
+		t.Fatalf("seed existing dest file: %v", err)
+	}
+
+	cfg := &Config{HashAlgo: "md5"}
+	ps := NewPipelineState(NoopProgressReporter{})
+	defer ps.Close()
+
+	got := ps.claimDestPath(destDir, "photo.jpg", &FileInfo{Path: a, Size: int64(len("new content"))}, cfg)
+	if got == existing {
+		t.Fatalf("expected a suffixed path distinct from the colliding %q", existing)
+	}
+}
@@ -0,0 +1,304 @@
+// Command mediadupes is the CLI for the mediadupes library: it wires cobra
+// flag parsing and a Bubble Tea progress UI around mediadupes.Run.
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+
+	"github.com/charmbracelet/bubbles/progress"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/spf13/cobra"
+
+	"github.com/vicendominguez/mediadupes"
+)
+
+type progressMsg mediadupes.ProgressEvent
+
+// teaReporter forwards ProgressEvents into a running Bubble Tea program.
+type teaReporter struct {
+	program *tea.Program
+}
+
+func (r teaReporter) Report(ev mediadupes.ProgressEvent) {
+	r.program.Send(progressMsg(ev))
+}
+
+type model struct {
+	progress    progress.Model
+	stage       string
+	current     int64
+	total       int64
+	done        bool
+	verbose     bool
+	currentFile string
+	width       int
+	errors      []string
+	errorCount  int
+	summary     string
+}
+
+func (m model) Init() tea.Cmd { return nil }
+
+func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		return m, nil
+	case progressMsg:
+		if msg.Stage != "" {
+			m.stage = msg.Stage
+		}
+		if msg.Total > 0 {
+			m.current = msg.Current
+			m.total = msg.Total
+		}
+		m.currentFile = msg.CurrentFile
+		if msg.ErrorFile != "" {
+			errorEntry := fmt.Sprintf("%s: %s", msg.ErrorFile, msg.ErrorMsg)
+			m.errors = append(m.errors, errorEntry)
+			if len(m.errors) > 10 {
+				m.errors = m.errors[1:]
+			}
+			m.errorCount++
+		}
+		if msg.Done {
+			m.done = true
+			return m, tea.Quit
+		}
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			return m, tea.Quit
+		}
+		if msg.String() == "v" {
+			m.verbose = !m.verbose
+			return m, nil
+		}
+	}
+	return m, nil
+}
+
+func (m model) View() string {
+	if m.done {
+		return m.summary + "\n"
+	}
+	percent := 0.0
+	if m.total > 0 {
+		percent = float64(m.current) / float64(m.total)
+	}
+
+	view := fmt.Sprintf("\n%s\n%s %d/%d\n", m.stage, m.progress.ViewAs(percent), m.current, m.total)
+
+	if m.verbose && m.currentFile != "" {
+		boxWidth := m.width - 4
+		if boxWidth < 20 {
+			boxWidth = 20
+		}
+
+		displayPath := m.currentFile
+		if len(displayPath) > boxWidth-2 {
+			displayPath = "..." + displayPath[len(displayPath)-(boxWidth-5):]
+		}
+
+		boxStyle := lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(lipgloss.Color("63")).
+			Padding(0, 1).
+			Width(boxWidth)
+
+		view += "\n" + boxStyle.Render(fmt.Sprintf("Processing: %s", displayPath)) + "\n"
+	}
+
+	if len(m.errors) > 0 {
+		boxWidth := m.width - 4
+		if boxWidth < 30 {
+			boxWidth = 30
+		}
+
+		errorStyle := lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(lipgloss.Color("196")).
+			Padding(0, 1).
+			Width(boxWidth)
+
+		errorLines := fmt.Sprintf("⚠️  Errors (%d total) - Last %d:", m.errorCount, len(m.errors))
+		for _, err := range m.errors {
+			if len(err) > boxWidth-4 {
+				err = "..." + err[len(err)-(boxWidth-7):]
+			}
+			errorLines += "\n" + err
+		}
+
+		view += "\n" + errorStyle.Render(errorLines) + "\n"
+	}
+
+	return view
+}
+
+// formatSummary renders the completion summary from mediadupes.RunResult:
+// under --plan a styled breakdown of totals, savings, file types, EXIF
+// presence and timing (plus cache hits/misses when the cache is enabled),
+// otherwise the processed/unique/copied/failed counts.
+func formatSummary(cfg *mediadupes.Config, result mediadupes.RunResult) string {
+	if cfg.PlanOnly {
+		savings := result.Stats.TotalSize - result.Stats.UniqueSize
+		savingsPercent := 0.0
+		if result.Stats.TotalSize > 0 {
+			savingsPercent = float64(savings) / float64(result.Stats.TotalSize) * 100
+		}
+
+		titleStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("86"))
+		labelStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+		valueStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("255"))
+		savingsStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("82"))
+
+		cacheLine := ""
+		if cfg.CacheEnabled {
+			cacheLine = fmt.Sprintf("\n\n%s\n  %s %d\n  %s %d",
+				labelStyle.Render("Cache:"),
+				labelStyle.Render("  Hits:"), result.CacheHits,
+				labelStyle.Render("  Misses:"), result.CacheMisses)
+		}
+
+		return fmt.Sprintf("\n%s\n\n%s\n  %s %s\n  %s %s\n  %s %d\n  %s %s\n\n%s\n  %s %d files\n  %s %d files\n\n%s\n  %s %d files\n  %s %d files\n\n%s\n  %s %d\n  %s %.1fs%s",
+			titleStyle.Render("Summary"),
+			labelStyle.Render("Files:"),
+			labelStyle.Render("  Total:"), valueStyle.Render(fmt.Sprintf("%d (%s)", result.Processed, formatBytes(result.Stats.TotalSize))),
+			labelStyle.Render("  Unique:"), valueStyle.Render(fmt.Sprintf("%d (%s)", result.Unique, formatBytes(result.Stats.UniqueSize))),
+			labelStyle.Render("  Duplicates:"), result.Processed-result.Unique,
+			labelStyle.Render("  Savings:"), savingsStyle.Render(fmt.Sprintf("%s (%.1f%%)", formatBytes(savings), savingsPercent)),
+			labelStyle.Render("By Type:"),
+			labelStyle.Render("  Images:"), result.Stats.TotalImages,
+			labelStyle.Render("  Videos:"), result.Stats.TotalVideos,
+			labelStyle.Render("Metadata:"),
+			labelStyle.Render("  With EXIF:"), result.Stats.WithMeta,
+			labelStyle.Render("  Without EXIF:"), result.Stats.WithoutMeta,
+			labelStyle.Render("Performance:"),
+			labelStyle.Render("  Workers:"), cfg.Workers,
+			labelStyle.Render("  Time:"), result.Elapsed.Seconds(), cacheLine)
+	}
+
+	summary := fmt.Sprintf("\nComplete!\n   Processed: %d files\n   Unique: %d files\n   Copied: %d files\n   Failed: %d files (%d scan, %d copy)",
+		result.Processed, result.Unique, result.Copied, result.FailedScan+result.FailedCopy, result.FailedScan, result.FailedCopy)
+	if cfg.CacheEnabled {
+		summary += fmt.Sprintf("\n   Cache: %d hits, %d misses", result.CacheHits, result.CacheMisses)
+	}
+	return summary
+}
+
+func formatBytes(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
+
+func runCLI(cfg *mediadupes.Config) error {
+	prog := progress.New(progress.WithDefaultGradient())
+	m := model{progress: prog, verbose: cfg.Verbose, width: 80}
+
+	p := tea.NewProgram(m)
+	uiDone := make(chan struct{})
+	go func() {
+		defer close(uiDone)
+		if _, err := p.Run(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error running UI: %v\n", err)
+		}
+	}()
+
+	result, err := mediadupes.Run(cfg, teaReporter{program: p})
+	if err != nil {
+		p.Quit()
+		<-uiDone
+		return err
+	}
+
+	p.Send(progressMsg{Done: true})
+	<-uiDone
+
+	fmt.Println(formatSummary(cfg, result))
+	return nil
+}
+
+func main() {
+	var source, dest string
+	var workers, copyParallel int
+	var imageExts, videoExts, excludeStr, includeStr, dedupMode, hashAlgo, layout, action, cachePath string
+	var noRecursive, noMeta, noDedup, planOnly, showVersion, oneDir, verbose, noCache, cacheInvalidate bool
+
+	rootCmd := &cobra.Command{
+		Use:   "mediadupes",
+		Short: "Deduplicate photos and videos based on metadata",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if showVersion {
+				fmt.Printf("mediadupes version %s\n", mediadupes.Version)
+				return nil
+			}
+
+			if !cmd.Flags().Changed("source") && !cmd.Flags().Changed("dest") && !planOnly {
+				return cmd.Help()
+			}
+
+			if !mediadupes.ValidDedupMode(dedupMode) {
+				return fmt.Errorf("invalid --dedup-mode %q (want \"basename\" or \"content\")", dedupMode)
+			}
+			if !mediadupes.ValidHashAlgo(hashAlgo) {
+				return fmt.Errorf("invalid --hash-algo %q (want \"md5\", \"sha256\" or \"blake3\")", hashAlgo)
+			}
+			if !mediadupes.ValidLayout(layout) {
+				return fmt.Errorf("invalid --layout %q (want \"mirror\", \"date\" or \"date-content\")", layout)
+			}
+			if !mediadupes.ValidAction(action) {
+				return fmt.Errorf("invalid --action %q (want \"copy\", \"hardlink\", \"reflink\", \"symlink\" or \"move\")", action)
+			}
+
+			cfg, err := mediadupes.BuildConfig(
+				source, dest, workers, copyParallel,
+				imageExts, videoExts, excludeStr, includeStr, dedupMode, hashAlgo, layout, action, cachePath,
+				noRecursive, noMeta, noDedup, planOnly, oneDir, verbose, noCache, cacheInvalidate,
+				cmd.Flags().Changed("exclude"),
+			)
+			if err != nil {
+				return err
+			}
+
+			return runCLI(cfg)
+		},
+	}
+
+	rootCmd.Flags().StringVarP(&source, "source", "s", ".", "Source directory (default: current directory)")
+	rootCmd.Flags().StringVarP(&dest, "dest", "d", "MEDIADUPES", "Destination directory (default: MEDIADUPES)")
+	rootCmd.Flags().IntVarP(&workers, "workers", "w", runtime.NumCPU(), "Number of workers for scanning and EXIF processing (CPU-bound, default: CPU count)")
+	rootCmd.Flags().IntVarP(&copyParallel, "copy-parallel", "c", 4, "Parallel file copy operations (I/O-bound, default: 4, increase for SSDs)")
+	rootCmd.Flags().StringVar(&imageExts, "image-exts", ".jpg,.jpeg,.png,.heic,.heif", "Image extensions (default: .jpg,.jpeg,.png,.heic,.heif)")
+	rootCmd.Flags().StringVar(&videoExts, "video-exts", ".mp4,.mov,.avi,.mkv,.m4v", "Video extensions (default: .mp4,.mov,.avi,.mkv,.m4v)")
+	rootCmd.Flags().StringVar(&excludeStr, "exclude", "", "Comma-separated doublestar glob patterns to exclude (default: paths starting with '.', anywhere in the tree); prefix a pattern with '!' to carve out an exception")
+	rootCmd.Flags().StringVar(&includeStr, "include", "", "Comma-separated doublestar glob patterns; when set, only matching paths are deduped (evaluated after --exclude, so an --include can override a broader --exclude)")
+	rootCmd.Flags().BoolVar(&noRecursive, "no-recursive", false, "Disable recursive directory scanning (default: false, recursive enabled)")
+	rootCmd.Flags().BoolVar(&noMeta, "no-meta", false, "Disable metadata/EXIF checking (default: false, metadata enabled)")
+	rootCmd.Flags().BoolVar(&noDedup, "no-dedup", false, "Disable deduplication (default: false, dedup enabled)")
+	rootCmd.Flags().StringVar(&dedupMode, "dedup-mode", mediadupes.DefaultDedupMode, "Duplicate detection mode: \"basename\" or \"content\" (content hashes file bytes instead of matching names)")
+	rootCmd.Flags().StringVar(&hashAlgo, "hash-algo", mediadupes.DefaultHashAlgo, "Hash algorithm for --dedup-mode=content: \"md5\", \"sha256\" or \"blake3\"")
+	rootCmd.Flags().BoolVar(&planOnly, "plan", false, "Show space savings estimate without copying (default: false)")
+	rootCmd.Flags().BoolVar(&oneDir, "one-dir", false, "Copy all files to dest root without subdirectories (default: false)")
+	rootCmd.Flags().StringVar(&layout, "layout", mediadupes.DefaultLayout, "Output layout: \"mirror\" (default, honors --one-dir), \"date\" (dest/YYYY/MM/basename from EXIF, falls back to mtime), or \"date-content\" (date layout symlinked to a content-addressed blob store)")
+	rootCmd.Flags().StringVar(&action, "action", mediadupes.DefaultAction, "How to place files in dest: \"copy\", \"hardlink\", \"reflink\" (same-filesystem only), \"symlink\", or \"move\"")
+	rootCmd.Flags().StringVar(&cachePath, "cache", "", "Path to the persistent scan cache (default: <dest>/.mediadupes-cache.db)")
+	rootCmd.Flags().BoolVar(&noCache, "no-cache", false, "Disable the persistent scan cache (default: false, cache enabled)")
+	rootCmd.Flags().BoolVar(&cacheInvalidate, "cache-invalidate", false, "Ignore any existing scan cache and rebuild it from scratch")
+	rootCmd.Flags().BoolVarP(&verbose, "verbose", "V", false, "Show currently processing files in real-time (default: false)")
+	rootCmd.Flags().BoolVarP(&showVersion, "version", "v", false, "Show version information")
+
+	if err := rootCmd.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,128 @@
+package mediadupes
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTempFile(t *testing.T, dir, name string, content []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+	return path
+}
+
+func runDeduplicateByContent(t *testing.T, cfg *Config, infos []FileInfo) map[string]*FileInfo {
+	t.Helper()
+	ps := NewPipelineState(NoopProgressReporter{})
+	defer ps.Close()
+
+	resultChan := make(chan FileInfo, len(infos))
+	for _, info := range infos {
+		resultChan <- info
+	}
+	close(resultChan)
+
+	dedup, _ := deduplicateByContent(resultChan, cfg, ps)
+	return dedup
+}
+
+// TestDeduplicateByContentDistinctSizes verifies that files with unique
+// sizes never pay for hashing and are kept as singletons.
+func TestDeduplicateByContentDistinctSizes(t *testing.T) {
+	dir := t.TempDir()
+	a := writeTempFile(t, dir, "a.jpg", []byte("aaaa"))
+	b := writeTempFile(t, dir, "b.jpg", []byte("bb"))
+
+	cfg := &Config{HashAlgo: "md5", DedupMode: dedupModeContent, Workers: 2}
+	dedup := runDeduplicateByContent(t, cfg, []FileInfo{
+		{Path: a, Size: 4},
+		{Path: b, Size: 2},
+	})
+
+	if len(dedup) != 2 {
+		t.Fatalf("expected 2 unique files, got %d", len(dedup))
+	}
+	for _, fi := range dedup {
+		if fi.Hash != "" {
+			t.Errorf("expected %s to skip hashing, got hash %q", fi.Path, fi.Hash)
+		}
+	}
+}
+
+// TestDeduplicateByContentSameSizeDistinctContent verifies that two files
+// sharing a size but not their full content are both kept, not collapsed
+// on a shared partial hash.
+func TestDeduplicateByContentSameSizeDistinctContent(t *testing.T) {
+	dir := t.TempDir()
+	prefix := bytes.Repeat([]byte("x"), partialHashBytes)
+	contentA := append(append([]byte{}, prefix...), "AAAA"...)
+	contentB := append(append([]byte{}, prefix...), "BBBB"...)
+	a := writeTempFile(t, dir, "a.jpg", contentA)
+	b := writeTempFile(t, dir, "b.jpg", contentB)
+
+	cfg := &Config{HashAlgo: "md5", DedupMode: dedupModeContent, Workers: 2}
+	dedup := runDeduplicateByContent(t, cfg, []FileInfo{
+		{Path: a, Size: int64(len(contentA))},
+		{Path: b, Size: int64(len(contentB))},
+	})
+
+	if len(dedup) != 2 {
+		t.Fatalf("expected 2 unique files, got %d", len(dedup))
+	}
+}
+
+// TestDeduplicateByContentSameSizeSameContent verifies that two files
+// sharing both size and full content collapse to a single kept entry.
+func TestDeduplicateByContentSameSizeSameContent(t *testing.T) {
+	dir := t.TempDir()
+	a := writeTempFile(t, dir, "a.jpg", []byte("identical"))
+	b := writeTempFile(t, dir, "b.jpg", []byte("identical"))
+
+	cfg := &Config{HashAlgo: "md5", DedupMode: dedupModeContent, Workers: 2}
+	dedup := runDeduplicateByContent(t, cfg, []FileInfo{
+		{Path: a, Size: 9},
+		{Path: b, Size: 9},
+	})
+
+	if len(dedup) != 1 {
+		t.Fatalf("expected 1 unique file after collapsing duplicates, got %d", len(dedup))
+	}
+}
+
+// TestDeduplicateByContentCachedFullHashSkipsRehash verifies that files
+// already carrying a confirmed full hash from the scan cache skip the
+// partial/full hashing pipeline entirely and still dedupe against each
+// other by their cached hash.
+func TestDeduplicateByContentCachedFullHashSkipsRehash(t *testing.T) {
+	dir := t.TempDir()
+	a := writeTempFile(t, dir, "a.jpg", []byte("identical"))
+	b := writeTempFile(t, dir, "b.jpg", []byte("identical"))
+
+	digest, err := hashFile(a, "md5", 0)
+	if err != nil {
+		t.Fatalf("hashFile: %v", err)
+	}
+
+	// With the files gone, any attempt to rehash them would fail and keep
+	// them apart, so collapsing to one entry shows the cached hash was used.
+	for _, p := range []string{a, b} {
+		if err := os.Remove(p); err != nil {
+			t.Fatalf("remove %s: %v", p, err)
+		}
+	}
+
+	cfg := &Config{HashAlgo: "md5", DedupMode: dedupModeContent, CacheEnabled: true, Workers: 2}
+	dedup := runDeduplicateByContent(t, cfg, []FileInfo{
+		{Path: a, Size: 9, Hash: digest, HashFull: true},
+		{Path: b, Size: 9, Hash: digest, HashFull: true},
+	})
+
+	if len(dedup) != 1 {
+		t.Fatalf("expected 1 unique file after collapsing duplicates, got %d", len(dedup))
+	}
+}
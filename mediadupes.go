@@ -0,0 +1,1535 @@
+// Package mediadupes deduplicates photos and videos based on metadata or
+// content hash. It is organized as a Source -> Parse -> Move pipeline: Run
+// wires the default stages together, and each stage is also exposed as a
+// package-level function so callers can swap in their own Source (e.g. an
+// S3 lister instead of a filesystem walk), Parse (an alternate metadata
+// extractor), or Move (a different sink) while reusing the rest.
+package mediadupes
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/barasher/go-exiftool"
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/zeebo/blake3"
+	"golang.org/x/sys/unix"
+)
+
+// Version is the library/CLI version string.
+const Version = "0.1.3"
+
+const (
+	dedupModeBaseName = "basename"
+	dedupModeContent  = "content"
+
+	// partialHashBytes is how much of a file we read on the first hashing
+	// pass; only files whose size and partial hash collide (or whose size
+	// matches a file the scan cache already fully hashed) pay for a full read.
+	partialHashBytes = 64 * 1024
+
+	layoutMirror      = "mirror"
+	layoutDate        = "date"
+	layoutDateContent = "date-content"
+
+	actionCopy     = "copy"
+	actionHardlink = "hardlink"
+	actionReflink  = "reflink"
+	actionSymlink  = "symlink"
+	actionMove     = "move"
+
+	// contentShardCount is the number of hex-prefix shard directories
+	// pre-created under <dest>/content before the copy fan-out starts,
+	// to avoid MkdirAll contention between workers.
+	contentShardCount = 256
+
+	// defaultCacheFileName is where the persistent scan cache lives
+	// relative to --dest when --cache isn't given explicitly.
+	defaultCacheFileName = ".mediadupes-cache.db"
+
+	// cacheSchemaVersion is bumped whenever cacheEntry's shape changes;
+	// loadCache discards the file and starts fresh on a mismatch.
+	cacheSchemaVersion = 1
+)
+
+type FileInfo struct {
+	Path         string
+	RelPath      string
+	Size         int64
+	HasMeta      bool
+	BaseName     string
+	CreationDate time.Time
+	ModTime      time.Time
+	IsImage      bool
+	Hash         string
+	HashFull     bool
+	HashFailed   bool
+}
+
+type Config struct {
+	Source          string
+	Dest            string
+	Workers         int
+	CopyParallel    int
+	ValidExts       map[string]bool
+	ImageExts       map[string]bool
+	Recursive       bool
+	CheckMeta       bool
+	EnableDedup     bool
+	DedupMode       string
+	HashAlgo        string
+	PlanOnly        bool
+	Layout          string
+	Action          string
+	OneDir          bool
+	Verbose         bool
+	Excludes        *PatternSet
+	CachePath       string
+	CacheEnabled    bool
+	CacheInvalidate bool
+}
+
+// ProgressEvent is a single progress update emitted while Run executes:
+// either a stage/position update, a per-file error, or (when Done is set)
+// the terminal event signaling the pipeline has finished.
+type ProgressEvent struct {
+	Stage       string
+	Current     int64
+	Total       int64
+	Done        bool
+	CurrentFile string
+	ErrorFile   string
+	ErrorMsg    string
+}
+
+// ProgressReporter receives ProgressEvents as Run executes. UI-rendering
+// implementations (e.g. a Bubble Tea program) live in the CLI, outside this
+// package; headless/library callers can pass NoopProgressReporter.
+type ProgressReporter interface {
+	Report(ev ProgressEvent)
+}
+
+// NoopProgressReporter discards every event. It's the zero-config choice
+// for library callers that don't need a UI.
+type NoopProgressReporter struct{}
+
+func (NoopProgressReporter) Report(ProgressEvent) {}
+
+// PipelineState holds the per-run counters, progress channel and scan cache
+// that Source/Parse/Move/Run thread through instead of touching package
+// globals, so Run (and the individual stages) can be called more than once
+// per process, and concurrently, without one run's counts leaking into
+// another's.
+type PipelineState struct {
+	Processed   atomic.Int64
+	Unique      atomic.Int64
+	Copied      atomic.Int64
+	FailedScan  atomic.Int64
+	FailedCopy  atomic.Int64
+	CacheHits   atomic.Int64
+	CacheMisses atomic.Int64
+
+	progChan   chan ProgressEvent
+	reportDone chan struct{}
+	cache      *scanCache
+
+	destMu     sync.Mutex
+	destClaims map[string]string
+}
+
+// NewPipelineState creates a PipelineState that forwards every ProgressEvent
+// it receives to reporter. Callers composing their own Source/Parse/Move
+// pipeline outside of Run should create one of these per run and call Close
+// once every stage has finished.
+func NewPipelineState(reporter ProgressReporter) *PipelineState {
+	ps := &PipelineState{
+		progChan:   make(chan ProgressEvent, 10),
+		reportDone: make(chan struct{}),
+		destClaims: make(map[string]string),
+	}
+	go func() {
+		defer close(ps.reportDone)
+		for ev := range ps.progChan {
+			reporter.Report(ev)
+		}
+	}()
+	return ps
+}
+
+// Close signals that no more ProgressEvents will be sent and waits for the
+// last one to reach the reporter.
+func (ps *PipelineState) Close() {
+	close(ps.progChan)
+	<-ps.reportDone
+}
+
+func getCreationDate(et *exiftool.Exiftool, filePath string) (time.Time, bool) {
+	metas := et.ExtractMetadata(filePath)
+	if len(metas) == 0 {
+		return time.Time{}, false
+	}
+	meta := metas[0]
+	if meta.Err != nil {
+		return time.Time{}, false
+	}
+
+	fields := []string{"DateTimeOriginal", "CreateDate", "CreationDate", "MediaCreateDate"}
+	for _, field := range fields {
+		if val, ok := meta.Fields[field]; ok {
+			if dateStr, ok := val.(string); ok {
+				formats := []string{
+					"2006:01:02 15:04:05",
+					"2006-01-02T15:04:05",
+					"2006-01-02 15:04:05",
+				}
+				for _, format := range formats {
+					if t, err := time.Parse(format, dateStr); err == nil {
+						return t, true
+					}
+				}
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// patternRule is one compiled entry of a PatternSet: a doublestar glob and
+// what Match should return when it's the last rule in the set to match a
+// given path (gitignore-style last-match-wins).
+type patternRule struct {
+	pattern string
+	result  bool
+}
+
+// PatternSet evaluates --exclude/--include globs against a relative path in
+// a single ordered pass, backed by github.com/bmatcuk/doublestar/v4 so
+// patterns can use "**" recursion (e.g. "**/Thumbs/**", "**/*.tmp") instead
+// of the bare filepath.Match/strings.Contains check this replaced. Exclude
+// patterns are evaluated first, include patterns after, so an --include can
+// carve an exception out of a broader --exclude; a leading "!" on either
+// flips that pattern's own result, letting either flag carve exceptions out
+// of itself the way a .gitignore file does.
+type PatternSet struct {
+	rules           []patternRule
+	caseInsensitive bool
+	defaultExcluded bool
+}
+
+// NewPatternSet compiles excludePatterns and includePatterns into a single
+// ordered PatternSet. defaultExcluded should be true when includePatterns is
+// non-empty, so a path survives only if some rule explicitly includes it.
+func NewPatternSet(excludePatterns, includePatterns []string, caseInsensitive bool) (*PatternSet, error) {
+	ps := &PatternSet{caseInsensitive: caseInsensitive, defaultExcluded: len(includePatterns) > 0}
+
+	appendRules := func(patterns []string, matchResult bool) error {
+		for _, raw := range patterns {
+			pattern := strings.TrimSpace(raw)
+			if pattern == "" {
+				continue
+			}
+			result := matchResult
+			if strings.HasPrefix(pattern, "!") {
+				pattern = pattern[1:]
+				result = !result
+			}
+			if !doublestar.ValidatePattern(pattern) {
+				return fmt.Errorf("invalid pattern %q", pattern)
+			}
+			ps.rules = append(ps.rules, patternRule{pattern: pattern, result: result})
+		}
+		return nil
+	}
+
+	if err := appendRules(excludePatterns, true); err != nil {
+		return nil, err
+	}
+	if err := appendRules(includePatterns, false); err != nil {
+		return nil, err
+	}
+
+	return ps, nil
+}
+
+// Match reports whether relPath should be excluded: the result of the last
+// rule that matches it, or the set's default when nothing matches.
+func (ps *PatternSet) Match(relPath string) bool {
+	relPath = filepath.ToSlash(relPath)
+	if ps.caseInsensitive {
+		relPath = strings.ToLower(relPath)
+	}
+
+	excluded := ps.defaultExcluded
+	for _, r := range ps.rules {
+		pattern := r.pattern
+		if ps.caseInsensitive {
+			pattern = strings.ToLower(pattern)
+		}
+		if ok, _ := doublestar.Match(pattern, relPath); ok {
+			excluded = r.result
+		}
+	}
+	return excluded
+}
+
+func walkFiles(source string, pathChan chan<- string, cfg *Config, ps *PipelineState) {
+	defer close(pathChan)
+
+	relOf := func(path string) string {
+		rel, err := filepath.Rel(source, path)
+		if err != nil {
+			return filepath.Base(path)
+		}
+		return rel
+	}
+
+	if !cfg.Recursive {
+		entries, err := os.ReadDir(source)
+		if err != nil {
+			return
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			path := filepath.Join(source, entry.Name())
+			if cfg.Excludes.Match(relOf(path)) {
+				continue
+			}
+			ext := strings.ToLower(filepath.Ext(path))
+			if cfg.ValidExts[ext] {
+				pathChan <- path
+			}
+		}
+		return
+	}
+
+	// Directories are pruned only by an explicit rule: with --include set, a
+	// directory that matches no rule is still walked so the files under it
+	// can be matched on their own paths.
+	dirRules := *cfg.Excludes
+	dirRules.defaultExcluded = false
+
+	if err := filepath.WalkDir(source, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if path == source {
+			return nil
+		}
+		if d.IsDir() {
+			if dirRules.Match(relOf(path)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if cfg.Excludes.Match(relOf(path)) {
+			return nil
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if cfg.ValidExts[ext] {
+			pathChan <- path
+		}
+		return nil
+	}); err != nil {
+		ps.progChan <- ProgressEvent{ErrorFile: source, ErrorMsg: fmt.Sprintf("walk dir: %v", err)}
+	}
+}
+
+// cacheEntry is what the persistent scan cache remembers per source path:
+// enough to know the file hasn't changed, plus the expensive-to-recompute
+// results (EXIF extraction and content hash) it lets us skip redoing.
+type cacheEntry struct {
+	ModTime      time.Time `json:"mtime"`
+	Size         int64     `json:"size"`
+	CreationDate time.Time `json:"creation_date"`
+	HasMeta      bool      `json:"has_meta"`
+	CheckMeta    bool      `json:"check_meta"`
+	Hash         string    `json:"hash,omitempty"`
+	HashAlgo     string    `json:"hash_algo,omitempty"`
+}
+
+// scanCache is a simple JSON persistent index of cacheEntry keyed by
+// source path, shared across the worker pool behind a single mutex (the
+// per-file critical section is tiny, so one lock is enough to avoid
+// contention without a dedicated writer goroutine).
+type scanCache struct {
+	Version int                   `json:"version"`
+	Entries map[string]cacheEntry `json:"entries"`
+
+	mu    sync.Mutex
+	path  string
+	dirty atomic.Bool
+}
+
+// loadCache reads path if it exists and matches cacheSchemaVersion,
+// otherwise it returns an empty cache so a schema bump or corrupt file
+// self-migrates instead of failing the run.
+func loadCache(path string) *scanCache {
+	c := &scanCache{Version: cacheSchemaVersion, Entries: make(map[string]cacheEntry), path: path}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return c
+	}
+
+	var onDisk scanCache
+	if err := json.Unmarshal(data, &onDisk); err != nil || onDisk.Version != cacheSchemaVersion {
+		return c
+	}
+
+	if onDisk.Entries != nil {
+		c.Entries = onDisk.Entries
+	}
+	return c
+}
+
+func (c *scanCache) lookup(path string, size int64, modTime time.Time) (cacheEntry, bool) {
+	c.mu.Lock()
+	entry, ok := c.Entries[path]
+	c.mu.Unlock()
+
+	if !ok || entry.Size != size || !entry.ModTime.Equal(modTime) {
+		return cacheEntry{}, false
+	}
+	return entry, true
+}
+
+func (c *scanCache) store(path string, entry cacheEntry) {
+	c.mu.Lock()
+	c.Entries[path] = entry
+	c.mu.Unlock()
+	c.dirty.Store(true)
+}
+
+// save writes the cache back to disk, atomically via a temp file + rename,
+// skipping the write entirely when no entry was stored this run. Every
+// scanned file stores its entry, so any run that scans a file writes it.
+func (c *scanCache) save() error {
+	if !c.dirty.Load() {
+		return nil
+	}
+
+	c.mu.Lock()
+	data, err := json.Marshal(c)
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.path)
+}
+
+func worker(et *exiftool.Exiftool, source string, pathChan <-chan string, resultChan chan<- FileInfo, total *atomic.Int64, cfg *Config, stage string, ps *PipelineState) {
+	for path := range pathChan {
+		stat, err := os.Stat(path)
+		if err != nil {
+			ps.FailedScan.Add(1)
+			ps.progChan <- ProgressEvent{ErrorFile: path, ErrorMsg: err.Error()}
+			continue
+		}
+
+		relPath, err := filepath.Rel(source, path)
+		if err != nil {
+			relPath = filepath.Base(path)
+		}
+
+		ext := filepath.Ext(path)
+		baseName := strings.TrimSuffix(filepath.Base(path), ext)
+
+		var creationDate time.Time
+		var hasMeta bool
+		var hashVal string
+		var hashFull bool
+		metaFromCache := false
+		if cfg.CacheEnabled && ps.cache != nil {
+			if entry, ok := ps.cache.lookup(path, stat.Size(), stat.ModTime()); ok {
+				ps.CacheHits.Add(1)
+				// Only trust the cached metadata/hash if they were produced
+				// under the same --no-meta/--hash-algo settings this run is
+				// using; otherwise toggling either flag across runs would
+				// silently keep serving stale values for unchanged files.
+				if entry.CheckMeta == cfg.CheckMeta {
+					creationDate, hasMeta = entry.CreationDate, entry.HasMeta
+					metaFromCache = true
+				}
+				if entry.HashAlgo == cfg.HashAlgo && entry.Hash != "" {
+					hashVal, hashFull = entry.Hash, true
+				}
+			} else {
+				ps.CacheMisses.Add(1)
+			}
+		}
+		if !metaFromCache && cfg.CheckMeta {
+			creationDate, hasMeta = getCreationDate(et, path)
+		}
+
+		info := FileInfo{
+			Path:         path,
+			RelPath:      relPath,
+			Size:         stat.Size(),
+			BaseName:     baseName,
+			HasMeta:      hasMeta,
+			CreationDate: creationDate,
+			ModTime:      stat.ModTime(),
+			IsImage:      cfg.ImageExts[strings.ToLower(ext)],
+			Hash:         hashVal,
+			HashFull:     hashFull,
+		}
+
+		if cfg.CacheEnabled && ps.cache != nil {
+			ps.cache.store(path, cacheEntry{
+				ModTime:      stat.ModTime(),
+				Size:         stat.Size(),
+				CreationDate: creationDate,
+				HasMeta:      hasMeta,
+				CheckMeta:    cfg.CheckMeta,
+				Hash:         hashVal,
+				HashAlgo:     cfg.HashAlgo,
+			})
+		}
+
+		resultChan <- info
+		current := ps.Processed.Add(1)
+		ps.progChan <- ProgressEvent{Stage: stage, Current: current, Total: total.Load(), CurrentFile: path}
+	}
+}
+
+type Stats struct {
+	TotalSize    int64
+	UniqueSize   int64
+	TotalImages  int
+	TotalVideos  int
+	UniqueImages int
+	UniqueVideos int
+	WithMeta     int
+	WithoutMeta  int
+}
+
+// preferCandidate decides whether candidate should replace existing as the
+// keeper of a duplicate group: files with EXIF metadata win, and among files
+// that agree on metadata presence the larger one wins.
+func preferCandidate(existing, candidate *FileInfo) bool {
+	return (candidate.HasMeta && !existing.HasMeta) ||
+		(candidate.HasMeta == existing.HasMeta && candidate.Size > existing.Size)
+}
+
+func deduplicate(resultChan <-chan FileInfo, cfg *Config, ps *PipelineState) (map[string]*FileInfo, Stats) {
+	if !cfg.EnableDedup {
+		return deduplicateIdentity(resultChan)
+	}
+	if cfg.DedupMode == dedupModeContent {
+		return deduplicateByContent(resultChan, cfg, ps)
+	}
+	return deduplicateByBaseName(resultChan)
+}
+
+// deduplicateIdentity keeps every scanned file, used when dedup is disabled.
+func deduplicateIdentity(resultChan <-chan FileInfo) (map[string]*FileInfo, Stats) {
+	dedup := make(map[string]*FileInfo)
+	stats := Stats{}
+
+	for info := range resultChan {
+		info := info
+		stats.TotalSize += info.Size
+		if info.IsImage {
+			stats.TotalImages++
+			stats.UniqueImages++
+		} else {
+			stats.TotalVideos++
+			stats.UniqueVideos++
+		}
+		if info.HasMeta {
+			stats.WithMeta++
+		} else {
+			stats.WithoutMeta++
+		}
+		dedup[info.Path] = &info
+		stats.UniqueSize += info.Size
+	}
+
+	return dedup, stats
+}
+
+func deduplicateByBaseName(resultChan <-chan FileInfo) (map[string]*FileInfo, Stats) {
+	dedup := make(map[string]*FileInfo)
+	var mu sync.Mutex
+	stats := Stats{}
+
+	updateCounters := func(info *FileInfo, add bool) {
+		delta := 1
+		if !add {
+			delta = -1
+		}
+		if info.IsImage {
+			stats.UniqueImages += delta
+		} else {
+			stats.UniqueVideos += delta
+		}
+	}
+
+	for info := range resultChan {
+		mu.Lock()
+		stats.TotalSize += info.Size
+
+		if info.IsImage {
+			stats.TotalImages++
+		} else {
+			stats.TotalVideos++
+		}
+
+		if info.HasMeta {
+			stats.WithMeta++
+		} else {
+			stats.WithoutMeta++
+		}
+
+		existing, exists := dedup[info.BaseName]
+
+		if !exists {
+			dedup[info.BaseName] = &info
+			stats.UniqueSize += info.Size
+			updateCounters(&info, true)
+		} else if preferCandidate(existing, &info) {
+			stats.UniqueSize = stats.UniqueSize - existing.Size + info.Size
+			updateCounters(existing, false)
+			updateCounters(&info, true)
+			dedup[info.BaseName] = &info
+		}
+		mu.Unlock()
+	}
+
+	return dedup, stats
+}
+
+// deduplicateByContent identifies duplicates by cryptographic content hash
+// instead of BaseName. It drains resultChan fully before hashing, since the
+// size-bucketing strategy below needs to see every file's size up front:
+// files are bucketed by Size, singleton buckets are skipped entirely, and
+// only the remaining buckets pay for a partial hash; files that still
+// collide on (size, partial hash) are fully hashed to confirm the match, as
+// are files sharing a size with a file whose full hash came from the cache.
+func deduplicateByContent(resultChan <-chan FileInfo, cfg *Config, ps *PipelineState) (map[string]*FileInfo, Stats) {
+	var infos []*FileInfo
+	stats := Stats{}
+
+	for info := range resultChan {
+		info := info
+		stats.TotalSize += info.Size
+		if info.IsImage {
+			stats.TotalImages++
+		} else {
+			stats.TotalVideos++
+		}
+		if info.HasMeta {
+			stats.WithMeta++
+		} else {
+			stats.WithoutMeta++
+		}
+		infos = append(infos, &info)
+	}
+
+	sizeBuckets := bucketBySize(infos)
+
+	// Files the scan cache already gave a confirmed full content hash
+	// skip the partial/full hashing pipeline entirely.
+	var candidates, singletons, alreadyHashed []*FileInfo
+	hashedSizes := make(map[int64]bool)
+	for _, bucket := range sizeBuckets {
+		if len(bucket) == 1 {
+			singletons = append(singletons, bucket[0])
+			continue
+		}
+		for _, info := range bucket {
+			if cfg.CacheEnabled && info.HashFull {
+				alreadyHashed = append(alreadyHashed, info)
+				hashedSizes[info.Size] = true
+			} else {
+				candidates = append(candidates, info)
+			}
+		}
+	}
+
+	hashFiles(candidates, cfg, partialHashBytes, "🔑 Hashing (partial)...", ps)
+
+	partialBuckets := make(map[string][]*FileInfo)
+	for _, info := range candidates {
+		if info.HashFailed {
+			// A file whose hash we couldn't compute can't be safely
+			// compared against anything else; keep it as its own entry
+			// rather than letting it collide with other failures on the
+			// empty-string Hash.
+			singletons = append(singletons, info)
+			continue
+		}
+		key := fmt.Sprintf("%d:%s", info.Size, info.Hash)
+		partialBuckets[key] = append(partialBuckets[key], info)
+	}
+
+	var fullCandidates []*FileInfo
+	for _, bucket := range partialBuckets {
+		// A lone partial-hash match can still duplicate a same-size file
+		// whose full hash came from the cache, so it's fully hashed too.
+		if len(bucket) == 1 && !hashedSizes[bucket[0].Size] {
+			singletons = append(singletons, bucket[0])
+			continue
+		}
+		fullCandidates = append(fullCandidates, bucket...)
+	}
+
+	hashFiles(fullCandidates, cfg, 0, "🔑 Hashing (full)...", ps)
+	for i := 0; i < len(fullCandidates); i++ {
+		if fullCandidates[i].HashFailed {
+			singletons = append(singletons, fullCandidates[i])
+			fullCandidates = append(fullCandidates[:i], fullCandidates[i+1:]...)
+			i--
+		}
+	}
+	fullCandidates = append(fullCandidates, alreadyHashed...)
+
+	dedup := make(map[string]*FileInfo, len(infos))
+	updateCounters := func(info *FileInfo, add bool) {
+		delta := 1
+		if !add {
+			delta = -1
+		}
+		if info.IsImage {
+			stats.UniqueImages += delta
+		} else {
+			stats.UniqueVideos += delta
+		}
+	}
+
+	for _, info := range singletons {
+		dedup[info.Path] = info
+		stats.UniqueSize += info.Size
+		updateCounters(info, true)
+	}
+
+	for hashKey, group := range bucketByHash(fullCandidates) {
+		keeper := group[0]
+		for _, info := range group[1:] {
+			if preferCandidate(keeper, info) {
+				keeper = info
+			}
+		}
+		dedup[hashKey] = keeper
+		stats.UniqueSize += keeper.Size
+		updateCounters(keeper, true)
+	}
+
+	return dedup, stats
+}
+
+func bucketBySize(infos []*FileInfo) map[int64][]*FileInfo {
+	buckets := make(map[int64][]*FileInfo)
+	for _, info := range infos {
+		buckets[info.Size] = append(buckets[info.Size], info)
+	}
+	return buckets
+}
+
+func bucketByHash(infos []*FileInfo) map[string][]*FileInfo {
+	buckets := make(map[string][]*FileInfo)
+	for _, info := range infos {
+		buckets[info.Hash] = append(buckets[info.Hash], info)
+	}
+	return buckets
+}
+
+// newHasher returns a fresh hash.Hash for the requested algorithm.
+func newHasher(algo string) (hash.Hash, error) {
+	switch algo {
+	case "md5":
+		return md5.New(), nil
+	case "sha256":
+		return sha256.New(), nil
+	case "blake3":
+		return blake3.New(), nil
+	default:
+		return nil, fmt.Errorf("unknown hash algorithm %q", algo)
+	}
+}
+
+// hashFile streams path through the configured hasher, the same way
+// copyFiles streams a file through io.Copy. limit, if > 0, caps the number
+// of bytes read so callers can compute a partial hash of the first N bytes.
+func hashFile(path, algo string, limit int64) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h, err := newHasher(algo)
+	if err != nil {
+		return "", err
+	}
+
+	var r io.Reader = f
+	if limit > 0 {
+		r = io.LimitReader(f, limit)
+	}
+
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// hashFiles runs a bounded worker pool (sized like the CPU-bound scan
+// workers, kept separate from CopyParallel's I/O-bound pool) that sets
+// Hash on each FileInfo and reports progress over ps.progChan. A file whose
+// hash can't be computed is marked HashFailed instead of being left with a
+// zero-value Hash, so callers don't let it collide with other unrelated
+// files that also failed to hash.
+func hashFiles(infos []*FileInfo, cfg *Config, limit int64, stage string, ps *PipelineState) {
+	if len(infos) == 0 {
+		return
+	}
+
+	jobChan := make(chan *FileInfo, len(infos))
+	for _, info := range infos {
+		jobChan <- info
+	}
+	close(jobChan)
+
+	var wg sync.WaitGroup
+	var done atomic.Int64
+	total := int64(len(infos))
+
+	for i := 0; i < cfg.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for info := range jobChan {
+				digest, err := hashFile(info.Path, cfg.HashAlgo, limit)
+				if err != nil {
+					info.HashFailed = true
+					ps.FailedScan.Add(1)
+					ps.progChan <- ProgressEvent{ErrorFile: info.Path, ErrorMsg: fmt.Sprintf("hash: %v", err)}
+					continue
+				}
+				info.Hash = digest
+				if limit == 0 {
+					info.HashFull = true
+					if cfg.CacheEnabled && ps.cache != nil {
+						ps.cache.store(info.Path, cacheEntry{
+							ModTime:      info.ModTime,
+							Size:         info.Size,
+							CreationDate: info.CreationDate,
+							HasMeta:      info.HasMeta,
+							CheckMeta:    cfg.CheckMeta,
+							Hash:         digest,
+							HashAlgo:     cfg.HashAlgo,
+						})
+					}
+				}
+				current := done.Add(1)
+				ps.progChan <- ProgressEvent{Stage: stage, Current: current, Total: total, CurrentFile: info.Path}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func formatBytes(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
+
+// destPathFor returns the mirror/one-dir/date destination path for fi
+// under cfg.Dest. For --layout=date(-content) it prefers the EXIF creation
+// date and falls back to the source file's mtime when EXIF is missing.
+func destPathFor(fi *FileInfo, cfg *Config, ps *PipelineState) string {
+	switch cfg.Layout {
+	case layoutDate, layoutDateContent:
+		date := fi.ModTime
+		if cfg.CheckMeta && fi.HasMeta && !fi.CreationDate.IsZero() {
+			date = fi.CreationDate
+		}
+		dir := filepath.Join(cfg.Dest, fmt.Sprintf("%04d", date.Year()), fmt.Sprintf("%02d", date.Month()))
+		return ps.claimDestPath(dir, filepath.Base(fi.Path), fi, cfg)
+	default:
+		if cfg.OneDir {
+			return ps.claimDestPath(cfg.Dest, filepath.Base(fi.Path), fi, cfg)
+		}
+		return filepath.Join(cfg.Dest, fi.RelPath)
+	}
+}
+
+// claimDestPath returns a path under dir for base that's reserved for fi.
+// Two different source files that land on the same dir+base (e.g. same
+// basename under --one-dir, or same basename and year/month under
+// --layout=date) would otherwise silently clobber each other on copy; here
+// the second one gets a "-2", "-3", ... suffix instead. A path already on
+// disk isn't automatically treated as a collision: if it has the same size
+// and content hash as fi, it's almost certainly fi itself placed by an
+// earlier run (the scan cache's whole point is recognizing that file as
+// unchanged), so that path is reused rather than piling up a fresh suffix
+// on every re-run. The content comparison (a full read-and-hash of both
+// files) only runs on that path-already-exists branch, and deliberately
+// outside ps.destMu, so one slow comparison on a re-run doesn't serialize
+// every other copyFiles worker behind it.
+func (ps *PipelineState) claimDestPath(dir, base string, fi *FileInfo, cfg *Config) string {
+	ext := filepath.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+	source := fi.Path
+
+	// Computed at most once, the first time a collision actually needs it,
+	// and reused across every candidate tried below instead of re-hashing
+	// fi.Path once per suffix.
+	var sourceHash string
+	haveSourceHash := false
+
+	candidate := filepath.Join(dir, base)
+	for n := 2; ; n++ {
+		ps.destMu.Lock()
+		owner, claimed := ps.destClaims[candidate]
+		ps.destMu.Unlock()
+
+		if claimed {
+			if owner == source {
+				return candidate
+			}
+		} else {
+			_, statErr := os.Stat(candidate)
+			switch {
+			case os.IsNotExist(statErr):
+				// Nothing at candidate yet; free to claim below.
+			case statErr != nil:
+				// Can't tell whether candidate collides (e.g. a permission
+				// error) without being able to stat it; claim it anyway and
+				// let the Sink surface the real filesystem error, rather
+				// than spinning here forever.
+			default:
+				if !haveSourceHash {
+					digest, err := hashFile(fi.Path, cfg.HashAlgo, 0)
+					sourceHash, haveSourceHash = digest, err == nil
+				}
+				if !haveSourceHash || !sameContent(sourceHash, fi.Size, candidate, cfg.HashAlgo) {
+					candidate = filepath.Join(dir, fmt.Sprintf("%s-%d%s", stem, n, ext))
+					continue
+				}
+			}
+
+			ps.destMu.Lock()
+			owner, claimed := ps.destClaims[candidate]
+			if !claimed || owner == source {
+				ps.destClaims[candidate] = source
+				ps.destMu.Unlock()
+				return candidate
+			}
+			ps.destMu.Unlock()
+		}
+		candidate = filepath.Join(dir, fmt.Sprintf("%s-%d%s", stem, n, ext))
+	}
+}
+
+// sameContent reports whether candidate has the given size and content
+// hash, used by claimDestPath to recognize a dest path already populated
+// by the same source file in a previous run.
+func sameContent(sourceHash string, sourceSize int64, candidate, hashAlgo string) bool {
+	infoB, err := os.Stat(candidate)
+	if err != nil || infoB.Size() != sourceSize {
+		return false
+	}
+
+	hashB, err := hashFile(candidate, hashAlgo, 0)
+	if err != nil {
+		return false
+	}
+	return sourceHash == hashB
+}
+
+// contentPathFor returns the content-addressed path for a file with the
+// given hash, sharded by the first byte of the digest: <dest>/content/<hh>/<hash><ext>.
+func contentPathFor(dest, hash, ext string) string {
+	return filepath.Join(dest, "content", hash[:2], hash+ext)
+}
+
+// prepContentShards pre-creates the 256 hex-prefix shard directories under
+// contentDir once, up front, so concurrent copy workers never race on
+// MkdirAll for the same shard.
+func prepContentShards(contentDir string) error {
+	for i := 0; i < contentShardCount; i++ {
+		if err := os.MkdirAll(filepath.Join(contentDir, fmt.Sprintf("%02x", i)), 0755); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Sink places a scanned file's bytes at destPath. copyFiles and
+// copyViaContentStore both delegate the actual on-disk placement to the
+// cfg.Action-selected Sink so --action=copy|hardlink|reflink|symlink|move
+// share one code path regardless of layout.
+type Sink interface {
+	Place(fi *FileInfo, destPath string, cfg *Config) error
+}
+
+func sinkFor(action string) Sink {
+	switch action {
+	case actionHardlink:
+		return hardlinkSink{}
+	case actionReflink:
+		return reflinkSink{}
+	case actionSymlink:
+		return symlinkSink{}
+	case actionMove:
+		return moveSink{}
+	default:
+		return copySink{}
+	}
+}
+
+// sameDevice reports whether the files at a and b live on the same
+// filesystem, per syscall.Stat_t.Dev.
+func sameDevice(a, b string) (bool, error) {
+	statA, err := os.Stat(a)
+	if err != nil {
+		return false, err
+	}
+	statB, err := os.Stat(b)
+	if err != nil {
+		return false, err
+	}
+	devA, ok := statA.Sys().(*syscall.Stat_t)
+	if !ok {
+		return false, fmt.Errorf("cannot determine device for %s", a)
+	}
+	devB, ok := statB.Sys().(*syscall.Stat_t)
+	if !ok {
+		return false, fmt.Errorf("cannot determine device for %s", b)
+	}
+	return devA.Dev == devB.Dev, nil
+}
+
+func applyChtimes(fi *FileInfo, destPath string, cfg *Config) {
+	if cfg.CheckMeta && fi.HasMeta && !fi.CreationDate.IsZero() {
+		_ = os.Chtimes(destPath, fi.CreationDate, fi.CreationDate)
+	}
+}
+
+// copySink streams fi.Path into destPath via io.Copy. It is also the
+// fallback reflinkSink uses when the filesystem can't clone the file.
+type copySink struct{}
+
+func (copySink) Place(fi *FileInfo, destPath string, cfg *Config) error {
+	src, err := os.Open(fi.Path)
+	if err != nil {
+		return fmt.Errorf("open: %w", err)
+	}
+	defer src.Close()
+
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+		return fmt.Errorf("mkdir: %w", err)
+	}
+
+	dst, err := os.Create(destPath)
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	defer dst.Close()
+
+	if _, err := io.Copy(dst, src); err != nil {
+		return fmt.Errorf("copy: %w", err)
+	}
+
+	applyChtimes(fi, destPath, cfg)
+	return nil
+}
+
+// hardlinkSink links destPath to fi.Path with os.Link, refusing to cross
+// filesystems since a hardlink cannot span devices. Hardlinks share the
+// source inode's metadata, so Chtimes is skipped. A destPath that already
+// links to fi.Path (e.g. a prior run, recognized again via the scan cache)
+// is treated as success rather than an EEXIST error, so re-runs against a
+// populated --dest are idempotent.
+type hardlinkSink struct{}
+
+func (hardlinkSink) Place(fi *FileInfo, destPath string, cfg *Config) error {
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+		return fmt.Errorf("mkdir: %w", err)
+	}
+
+	if same, err := sameDevice(fi.Path, filepath.Dir(destPath)); err != nil {
+		return fmt.Errorf("stat: %w", err)
+	} else if !same {
+		return fmt.Errorf("hardlink: %s and %s are on different filesystems", fi.Path, destPath)
+	}
+
+	if destInfo, err := os.Stat(destPath); err == nil {
+		srcInfo, err := os.Stat(fi.Path)
+		if err != nil {
+			return fmt.Errorf("stat: %w", err)
+		}
+		if os.SameFile(srcInfo, destInfo) {
+			return nil
+		}
+		return fmt.Errorf("hardlink: %s already exists and is not linked to %s", destPath, fi.Path)
+	}
+
+	if err := os.Link(fi.Path, destPath); err != nil {
+		return fmt.Errorf("hardlink: %w", err)
+	}
+	return nil
+}
+
+// reflinkSink uses the FICLONE ioctl to make destPath a copy-on-write clone
+// of fi.Path, sharing the underlying data blocks on filesystems that support
+// it (Btrfs, XFS, APFS). It refuses to cross filesystems like hardlinkSink,
+// and falls back to a regular copy when the filesystem doesn't support
+// reflinks (ENOTSUP) or the kernel can't do it across mount points (EXDEV).
+type reflinkSink struct{}
+
+func (reflinkSink) Place(fi *FileInfo, destPath string, cfg *Config) error {
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+		return fmt.Errorf("mkdir: %w", err)
+	}
+
+	same, err := sameDevice(fi.Path, filepath.Dir(destPath))
+	if err != nil {
+		return fmt.Errorf("stat: %w", err)
+	}
+	if !same {
+		return fmt.Errorf("reflink: %s and %s are on different filesystems", fi.Path, destPath)
+	}
+
+	src, err := os.Open(fi.Path)
+	if err != nil {
+		return fmt.Errorf("open: %w", err)
+	}
+	defer src.Close()
+
+	dst, err := os.Create(destPath)
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	defer dst.Close()
+
+	if err := unix.IoctlFileClone(int(dst.Fd()), int(src.Fd())); err != nil {
+		if err == unix.EOPNOTSUPP || err == unix.EXDEV {
+			dst.Close()
+			src.Close()
+			return copySink{}.Place(fi, destPath, cfg)
+		}
+		return fmt.Errorf("reflink: %w", err)
+	}
+
+	applyChtimes(fi, destPath, cfg)
+	return nil
+}
+
+// symlinkSink points destPath at fi.Path's absolute location instead of
+// copying any bytes. Timestamps on a symlink aren't meaningful here, so
+// Chtimes is skipped. A destPath that's already a symlink pointing at
+// fi.Path (e.g. a prior run, recognized again via the scan cache) is
+// treated as success rather than an EEXIST error, so re-runs against a
+// populated --dest are idempotent.
+type symlinkSink struct{}
+
+func (symlinkSink) Place(fi *FileInfo, destPath string, cfg *Config) error {
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+		return fmt.Errorf("mkdir: %w", err)
+	}
+
+	absSrc, err := filepath.Abs(fi.Path)
+	if err != nil {
+		return fmt.Errorf("resolve source path: %w", err)
+	}
+
+	if target, err := os.Readlink(destPath); err == nil {
+		if target == absSrc {
+			return nil
+		}
+		return fmt.Errorf("symlink: %s already exists and points elsewhere", destPath)
+	}
+
+	if err := os.Symlink(absSrc, destPath); err != nil {
+		return fmt.Errorf("symlink: %w", err)
+	}
+	return nil
+}
+
+// moveSink relocates fi.Path to destPath with os.Rename, which fails with
+// EXDEV if source and dest aren't on the same filesystem; that error is
+// returned as-is rather than silently falling back to a copy, since a move
+// that secretly becomes a copy would leave the source file behind unexpectedly.
+type moveSink struct{}
+
+func (moveSink) Place(fi *FileInfo, destPath string, cfg *Config) error {
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+		return fmt.Errorf("mkdir: %w", err)
+	}
+
+	if err := os.Rename(fi.Path, destPath); err != nil {
+		return fmt.Errorf("move: %w", err)
+	}
+
+	applyChtimes(fi, destPath, cfg)
+	return nil
+}
+
+func copyFiles(files map[string]*FileInfo, cfg *Config, ps *PipelineState, errChan chan<- error) {
+	if err := os.MkdirAll(cfg.Dest, 0755); err != nil {
+		ps.progChan <- ProgressEvent{ErrorFile: cfg.Dest, ErrorMsg: fmt.Sprintf("mkdir dest: %v", err)}
+		errChan <- fmt.Errorf("mkdir dest: %w", err)
+		return
+	}
+
+	if cfg.Layout == layoutDateContent {
+		if err := prepContentShards(filepath.Join(cfg.Dest, "content")); err != nil {
+			ps.progChan <- ProgressEvent{ErrorFile: cfg.Dest, ErrorMsg: fmt.Sprintf("prep content shards: %v", err)}
+			errChan <- fmt.Errorf("prep content shards: %w", err)
+			return
+		}
+	}
+
+	sink := sinkFor(cfg.Action)
+	sem := make(chan struct{}, cfg.CopyParallel)
+	var wg sync.WaitGroup
+	total := int64(len(files))
+
+	for _, info := range files {
+		wg.Add(1)
+		go func(fi *FileInfo) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			destPath := destPathFor(fi, cfg, ps)
+
+			var err error
+			if cfg.Layout == layoutDateContent {
+				err = copyViaContentStore(fi, destPath, cfg, sink)
+			} else {
+				err = sink.Place(fi, destPath, cfg)
+			}
+
+			if err != nil {
+				ps.FailedCopy.Add(1)
+				ps.progChan <- ProgressEvent{ErrorFile: fi.Path, ErrorMsg: err.Error()}
+				errChan <- fmt.Errorf("%s: %w", fi.Path, err)
+				return
+			}
+			current := ps.Copied.Add(1)
+			ps.progChan <- ProgressEvent{Stage: "💾 Copying files...", Current: current, Total: total, CurrentFile: fi.Path}
+		}(info)
+	}
+
+	wg.Wait()
+}
+
+// copyViaContentStore places fi into the content-addressed blob store via
+// sink (hashing it on demand if --dedup-mode=content hasn't already done
+// so) and symlinks the date-organized destPath to it, so files that share
+// content collapse to a single stored blob while remaining browsable by date.
+func copyViaContentStore(fi *FileInfo, destPath string, cfg *Config, sink Sink) error {
+	hashVal := fi.Hash
+	if !fi.HashFull {
+		// fi.Hash may only be a partial hash (e.g. a deduplicateByContent
+		// singleton that never reached the full-hash pass) — never trust
+		// it as the content-store key unless it's confirmed full.
+		digest, err := hashFile(fi.Path, cfg.HashAlgo, 0)
+		if err != nil {
+			return fmt.Errorf("hash: %w", err)
+		}
+		hashVal = digest
+	}
+
+	contentPath := contentPathFor(cfg.Dest, hashVal, filepath.Ext(fi.Path))
+
+	if _, err := os.Stat(contentPath); os.IsNotExist(err) {
+		if err := sink.Place(fi, contentPath, cfg); err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+		return fmt.Errorf("mkdir: %w", err)
+	}
+	_ = os.Remove(destPath)
+	if err := os.Symlink(contentPath, destPath); err != nil {
+		return fmt.Errorf("symlink: %w", err)
+	}
+
+	return nil
+}
+
+// Source walks cfg.Source and emits matching file paths on the returned
+// channel, which is closed once the walk finishes. Callers wanting a custom
+// source (e.g. a remote file lister) can write their own <-chan string
+// producer with the same shape and splice it in place of Source.
+func Source(cfg *Config, ps *PipelineState) <-chan string {
+	pathChan := make(chan string, 1000)
+	go walkFiles(cfg.Source, pathChan, cfg, ps)
+	return pathChan
+}
+
+// Parse consumes paths from pathChan across cfg.Workers exiftool-backed
+// workers and emits a FileInfo per path on the returned channel, which is
+// closed once every path has been processed. total is updated by the caller
+// as files are discovered so progress events can report against the right
+// denominator. A different Parse (e.g. a pure-Go EXIF reader) can be
+// substituted as long as it has this shape.
+func Parse(pathChan <-chan string, cfg *Config, total *atomic.Int64, ps *PipelineState) <-chan FileInfo {
+	resultChan := make(chan FileInfo, 100)
+
+	var wg sync.WaitGroup
+	for i := 0; i < cfg.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			et, err := exiftool.NewExiftool()
+			if err != nil {
+				ps.progChan <- ProgressEvent{ErrorFile: "exiftool", ErrorMsg: fmt.Sprintf("worker init failed: %v", err)}
+				return
+			}
+			defer et.Close()
+
+			worker(et, cfg.Source, pathChan, resultChan, total, cfg, "📁 Scanning files...", ps)
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(resultChan)
+	}()
+
+	return resultChan
+}
+
+// Move sinks the deduplicated files map to cfg.Dest (copy, or the
+// date/date-content layouts) and reports one error per failure (plus any
+// dest-setup failure) on the returned channel, which is closed once every
+// file has been handled. Swap in a different Move to relocate files a
+// different way.
+func Move(files map[string]*FileInfo, cfg *Config, ps *PipelineState) <-chan error {
+	errChan := make(chan error, len(files)+2)
+	go func() {
+		defer close(errChan)
+		copyFiles(files, cfg, ps, errChan)
+	}()
+	return errChan
+}
+
+// RunResult carries the numeric outcome of a Run call so callers can build
+// their own presentation; the library itself renders no UI.
+type RunResult struct {
+	Stats       Stats
+	Processed   int64
+	Unique      int64
+	Copied      int64
+	FailedScan  int64
+	FailedCopy  int64
+	CacheHits   int64
+	CacheMisses int64
+	Elapsed     time.Duration
+}
+
+// Run executes the default Source -> Parse -> dedup -> Move pipeline for
+// cfg, emitting ProgressEvents to reporter as it goes. Pass
+// NoopProgressReporter{} for headless use.
+func Run(cfg *Config, reporter ProgressReporter) (RunResult, error) {
+	if _, err := os.Stat(cfg.Source); os.IsNotExist(err) {
+		return RunResult{}, fmt.Errorf("source directory '%s' does not exist", cfg.Source)
+	}
+
+	ps := NewPipelineState(reporter)
+	defer ps.Close()
+
+	if cfg.CacheEnabled {
+		if cfg.CacheInvalidate {
+			ps.cache = &scanCache{Version: cacheSchemaVersion, Entries: make(map[string]cacheEntry), path: cfg.CachePath}
+		} else {
+			ps.cache = loadCache(cfg.CachePath)
+		}
+	}
+
+	ps.progChan <- ProgressEvent{Stage: "🔍 Counting files...", Current: 0, Total: 1}
+
+	var totalFiles atomic.Int64
+	if err := filepath.WalkDir(cfg.Source, func(path string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && cfg.ValidExts[strings.ToLower(filepath.Ext(path))] {
+			totalFiles.Add(1)
+		}
+		return nil
+	}); err != nil {
+		ps.progChan <- ProgressEvent{ErrorFile: cfg.Source, ErrorMsg: fmt.Sprintf("count files: %v", err)}
+	}
+
+	startTime := time.Now()
+	pathChan := Source(cfg, ps)
+	resultChan := Parse(pathChan, cfg, &totalFiles, ps)
+
+	dedup, stats := deduplicate(resultChan, cfg, ps)
+	ps.Unique.Store(int64(len(dedup)))
+	elapsed := time.Since(startTime)
+
+	if !cfg.PlanOnly {
+		for range Move(dedup, cfg, ps) {
+			// per-file errors are already reported via ps.progChan by copyFiles
+		}
+	}
+
+	if cfg.CacheEnabled {
+		if err := ps.cache.save(); err != nil {
+			ps.progChan <- ProgressEvent{ErrorFile: cfg.CachePath, ErrorMsg: fmt.Sprintf("save cache: %v", err)}
+		}
+	}
+
+	ps.progChan <- ProgressEvent{Done: true}
+
+	return RunResult{
+		Stats:       stats,
+		Processed:   ps.Processed.Load(),
+		Unique:      ps.Unique.Load(),
+		Copied:      ps.Copied.Load(),
+		FailedScan:  ps.FailedScan.Load(),
+		FailedCopy:  ps.FailedCopy.Load(),
+		CacheHits:   ps.CacheHits.Load(),
+		CacheMisses: ps.CacheMisses.Load(),
+		Elapsed:     elapsed,
+	}, nil
+}
+
+// defaultExcludePattern excludes any path component starting with '.'
+// (anywhere in the tree) when the user hasn't supplied --exclude explicitly.
+const defaultExcludePattern = "**/.*"
+
+// BuildConfig turns CLI-shaped primitives into a Config, parsing the
+// comma-separated extension/exclude/include lists, compiling them into a
+// PatternSet, and resolving the default cache path relative to dest.
+func BuildConfig(source, dest string, workers, copyParallel int, imageExts, videoExts, excludeStr, includeStr, dedupMode, hashAlgo, layout, action, cachePath string, noRecursive, noMeta, noDedup, planOnly, oneDir, verbose, noCache, cacheInvalidate bool, excludeChanged bool) (*Config, error) {
+	validExts := make(map[string]bool)
+	imageExtsMap := make(map[string]bool)
+
+	for _, ext := range strings.Split(imageExts, ",") {
+		if ext = strings.TrimSpace(ext); ext != "" {
+			validExts[strings.ToLower(ext)] = true
+			imageExtsMap[strings.ToLower(ext)] = true
+		}
+	}
+	for _, ext := range strings.Split(videoExts, ",") {
+		if ext = strings.TrimSpace(ext); ext != "" {
+			validExts[strings.ToLower(ext)] = true
+		}
+	}
+
+	var excludePatterns []string
+	if excludeChanged {
+		excludePatterns = strings.Split(excludeStr, ",")
+	} else {
+		excludePatterns = []string{defaultExcludePattern}
+	}
+	var includePatterns []string
+	if includeStr != "" {
+		includePatterns = strings.Split(includeStr, ",")
+	}
+
+	caseInsensitive := runtime.GOOS == "windows" || runtime.GOOS == "darwin"
+	excludes, err := NewPatternSet(excludePatterns, includePatterns, caseInsensitive)
+	if err != nil {
+		return nil, fmt.Errorf("compile --exclude/--include patterns: %w", err)
+	}
+
+	if cachePath == "" {
+		cachePath = filepath.Join(dest, defaultCacheFileName)
+	}
+
+	return &Config{
+		Source:          source,
+		Dest:            dest,
+		Workers:         workers,
+		CopyParallel:    copyParallel,
+		ValidExts:       validExts,
+		ImageExts:       imageExtsMap,
+		Recursive:       !noRecursive,
+		CheckMeta:       !noMeta,
+		EnableDedup:     !noDedup,
+		DedupMode:       dedupMode,
+		HashAlgo:        hashAlgo,
+		PlanOnly:        planOnly,
+		Layout:          layout,
+		Action:          action,
+		OneDir:          oneDir,
+		Verbose:         verbose,
+		Excludes:        excludes,
+		CachePath:       cachePath,
+		CacheEnabled:    !noCache,
+		CacheInvalidate: cacheInvalidate,
+	}, nil
+}
+
+// ValidDedupMode reports whether mode is a recognized --dedup-mode value.
+func ValidDedupMode(mode string) bool {
+	return mode == dedupModeBaseName || mode == dedupModeContent
+}
+
+// ValidHashAlgo reports whether algo is a recognized --hash-algo value.
+func ValidHashAlgo(algo string) bool {
+	switch algo {
+	case "md5", "sha256", "blake3":
+		return true
+	default:
+		return false
+	}
+}
+
+// ValidLayout reports whether layout is a recognized --layout value.
+func ValidLayout(layout string) bool {
+	switch layout {
+	case layoutMirror, layoutDate, layoutDateContent:
+		return true
+	default:
+		return false
+	}
+}
+
+// ValidAction reports whether action is a recognized --action value.
+func ValidAction(action string) bool {
+	switch action {
+	case actionCopy, actionHardlink, actionReflink, actionSymlink, actionMove:
+		return true
+	default:
+		return false
+	}
+}
+
+// Default values for --dedup-mode, --hash-algo, --layout and --action.
+const (
+	DefaultDedupMode = dedupModeBaseName
+	DefaultHashAlgo  = "md5"
+	DefaultLayout    = layoutMirror
+	DefaultAction    = actionCopy
+)
@@ -0,0 +1,90 @@
+package mediadupes
+
+import "testing"
+
+// TestPatternSetExcludeOnly verifies a plain --exclude pattern excludes only
+// the paths it matches.
+func TestPatternSetExcludeOnly(t *testing.T) {
+	ps, err := NewPatternSet([]string{"**/*.tmp"}, nil, false)
+	if err != nil {
+		t.Fatalf("NewPatternSet: %v", err)
+	}
+
+	if !ps.Match("foo/bar.tmp") {
+		t.Errorf("expected foo/bar.tmp to be excluded")
+	}
+	if ps.Match("foo/bar.jpg") {
+		t.Errorf("expected foo/bar.jpg to not be excluded")
+	}
+}
+
+// TestPatternSetIncludeDefaultsExcluded verifies that once any include
+// pattern is given, a path survives only if some rule explicitly includes
+// it.
+func TestPatternSetIncludeDefaultsExcluded(t *testing.T) {
+	ps, err := NewPatternSet(nil, []string{"**/*.jpg"}, false)
+	if err != nil {
+		t.Fatalf("NewPatternSet: %v", err)
+	}
+
+	if ps.Match("foo/bar.jpg") {
+		t.Errorf("expected foo/bar.jpg to be included (not excluded)")
+	}
+	if !ps.Match("foo/bar.png") {
+		t.Errorf("expected foo/bar.png to default-exclude since it matches no include rule")
+	}
+}
+
+// TestPatternSetNegationCarvesException verifies a leading "!" on a pattern
+// flips that pattern's own result, letting includes/excludes carve an
+// exception out of a broader rule the way a .gitignore file does.
+func TestPatternSetNegationCarvesException(t *testing.T) {
+	ps, err := NewPatternSet([]string{"**/*.tmp", "!keep/**"}, nil, false)
+	if err != nil {
+		t.Fatalf("NewPatternSet: %v", err)
+	}
+
+	if !ps.Match("build/out.tmp") {
+		t.Errorf("expected build/out.tmp to be excluded")
+	}
+	if ps.Match("keep/out.tmp") {
+		t.Errorf("expected keep/out.tmp to be carved out as an exception")
+	}
+}
+
+// TestPatternSetLastMatchingRuleWins verifies that when multiple rules
+// match the same path, the result of the last one applies.
+func TestPatternSetLastMatchingRuleWins(t *testing.T) {
+	ps, err := NewPatternSet([]string{"**/*.jpg", "!**/keep-*.jpg"}, nil, false)
+	if err != nil {
+		t.Fatalf("NewPatternSet: %v", err)
+	}
+
+	if ps.Match("photos/keep-me.jpg") {
+		t.Errorf("expected photos/keep-me.jpg to survive the later exception rule")
+	}
+	if !ps.Match("photos/other.jpg") {
+		t.Errorf("expected photos/other.jpg to still be excluded")
+	}
+}
+
+// TestPatternSetCaseInsensitive verifies that caseInsensitive folds both the
+// pattern and the candidate path before matching.
+func TestPatternSetCaseInsensitive(t *testing.T) {
+	ps, err := NewPatternSet([]string{"**/*.JPG"}, nil, true)
+	if err != nil {
+		t.Fatalf("NewPatternSet: %v", err)
+	}
+
+	if !ps.Match("foo/bar.jpg") {
+		t.Errorf("expected case-insensitive match to exclude foo/bar.jpg")
+	}
+}
+
+// TestPatternSetInvalidPattern verifies that an unparsable glob pattern is
+// rejected at construction time rather than failing silently on every path.
+func TestPatternSetInvalidPattern(t *testing.T) {
+	if _, err := NewPatternSet([]string{"["}, nil, false); err == nil {
+		t.Fatalf("expected an error for an invalid pattern")
+	}
+}